@@ -0,0 +1,162 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// diskCache is an http.RoundTripper that caches responses on disk, keyed
+// by request URL, and revalidates stale entries with the origin server
+// using If-None-Match/If-Modified-Since before falling back to a full
+// fetch. It makes repeated cmd/events runs in CI only pay for groups that
+// actually changed.
+type diskCache struct {
+	dir  string
+	ttl  time.Duration
+	next http.RoundTripper
+}
+
+// newDiskCache returns a diskCache rooted at dir. A zero ttl means entries
+// are always revalidated (but still skip the download when the server
+// replies 304 Not Modified).
+func newDiskCache(dir string, ttl time.Duration) *diskCache {
+	return &diskCache{dir: dir, ttl: ttl, next: http.DefaultTransport}
+}
+
+func (d *diskCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := d.entryPath(req.URL.String())
+	entry, _ := readCacheEntry(path)
+
+	if entry != nil && d.ttl > 0 && time.Since(entry.storedAt) < d.ttl {
+		return entry.response(req)
+	}
+	if entry != nil {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := d.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		entry.storedAt = time.Now()
+		writeCacheEntry(path, entry)
+		return entry.response(req)
+	}
+	if resp.StatusCode == http.StatusOK {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		newEntry := &cacheEntry{
+			status:       resp.StatusCode,
+			header:       resp.Header.Clone(),
+			body:         body,
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+			storedAt:     time.Now(),
+		}
+		writeCacheEntry(path, newEntry)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+func (d *diskCache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".cache")
+}
+
+// cacheEntry is the on-disk representation of a cached response: an HTTP/1.1
+// status line and headers (read with http.ReadResponse), a blank line, and
+// the body. The time it was stored is kept among the headers, as an RFC3339
+// X-Cache-Stored-At value.
+type cacheEntry struct {
+	status       int
+	header       http.Header
+	body         []byte
+	etag         string
+	lastModified string
+	storedAt     time.Time
+}
+
+func (e *cacheEntry) response(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: e.status,
+		Status:     http.StatusText(e.status),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     e.header.Clone(),
+		Body:       ioutil.NopCloser(bytes.NewReader(e.body)),
+		Request:    req,
+	}, nil
+}
+
+func readCacheEntry(path string) (*cacheEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	storedAt, _ := time.Parse(time.RFC3339, resp.Header.Get("X-Cache-Stored-At"))
+	return &cacheEntry{
+		status:       resp.StatusCode,
+		header:       resp.Header,
+		body:         body,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		storedAt:     storedAt,
+	}, nil
+}
+
+func writeCacheEntry(path string, e *cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	header := e.header.Clone()
+	header.Set("X-Cache-Stored-At", e.storedAt.Format(time.RFC3339))
+	resp := &http.Response{
+		StatusCode:    e.status,
+		Status:        http.StatusText(e.status),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, dump, 0o644)
+}
@@ -0,0 +1,360 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// icsConfig is the YAML schema read from the -ics-config flag. It lists the
+// iCalendar feeds belonging to groups that no longer publish to Meetup.
+type icsConfig struct {
+	Feeds []icsFeed `yaml:"feeds"`
+}
+
+// icsFeed describes a single VCALENDAR feed to poll for upcoming events.
+type icsFeed struct {
+	URL     string `yaml:"url"`
+	City    string `yaml:"city"`
+	Country string `yaml:"country"`
+	State   string `yaml:"state"`
+}
+
+// icsSource is an EventSource that reads VEVENT components from a list of
+// iCalendar (RFC 5545) feeds, expanding RRULE recurrences to find the next
+// upcoming occurrence per calendar.
+type icsSource struct {
+	feeds []icsFeed
+	now   func() time.Time
+}
+
+// loadICSConfig reads and parses the YAML feed list at path.
+func loadICSConfig(path string) (*icsConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ics config %q: %w", path, err)
+	}
+	defer f.Close()
+	var cfg icsConfig
+	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode ics config %q: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func newICSSource(cfg *icsConfig) *icsSource {
+	return &icsSource{feeds: cfg.Feeds, now: time.Now}
+}
+
+// Events implements EventSource.
+func (s *icsSource) Events() ([]EventData, error) {
+	var events []EventData
+	for _, feed := range s.feeds {
+		vevents, err := fetchVEVENTs(feed.URL)
+		if err != nil {
+			log.Printf("skipping ics feed %q: %v", feed.URL, err)
+			continue
+		}
+		best, ok := nextUpcoming(vevents, s.now())
+		if !ok {
+			continue
+		}
+		events = append(events, icsEventToData(best, feed))
+	}
+	return events, nil
+}
+
+// nextUpcoming returns the VEVENT, expanded through its RRULE if present,
+// whose next occurrence is closest to (but not before) now.
+func nextUpcoming(vevents []vevent, now time.Time) (vevent, bool) {
+	var best vevent
+	var bestStart time.Time
+	found := false
+	for _, e := range vevents {
+		start, ok := e.nextOccurrence(now)
+		if !ok {
+			continue
+		}
+		if !found || start.Before(bestStart) {
+			best, bestStart, found = e, start, true
+		}
+	}
+	if found {
+		best.resolvedStart = bestStart
+	}
+	return best, found
+}
+
+func icsEventToData(e vevent, feed icsFeed) EventData {
+	start := e.resolvedStart
+	return EventData{
+		City:        feed.City,
+		Country:     feed.Country,
+		Description: e.description,
+		ID:          e.uid,
+		LocalDate:   start.Format("Jan 2, 2006"),
+		LocalTime:   start.Format(time.RFC3339),
+		Name:        e.summary,
+		State:       feed.State,
+		URL:         e.url,
+		Source:      eventSourceICS,
+	}
+}
+
+// vevent is a parsed VEVENT component.
+type vevent struct {
+	uid           string
+	summary       string
+	description   string
+	location      string
+	url           string
+	dtstart       time.Time
+	dtend         time.Time
+	rrule         string
+	resolvedStart time.Time
+}
+
+// nextOccurrence returns the first occurrence of e that is not before now,
+// expanding RRULE (FREQ=DAILY/WEEKLY/MONTHLY/YEARLY with an optional
+// INTERVAL, terminated by UNTIL or COUNT) when present, or e.dtstart itself
+// otherwise.
+func (e vevent) nextOccurrence(now time.Time) (time.Time, bool) {
+	if e.dtstart.IsZero() {
+		return time.Time{}, false
+	}
+	if e.rrule == "" {
+		if e.dtstart.Before(now) {
+			return time.Time{}, false
+		}
+		return e.dtstart, true
+	}
+	freq, interval, until, count := parseRRule(e.rrule)
+	if freq == "" {
+		return time.Time{}, false
+	}
+	occ := e.dtstart
+	for i := 0; count == 0 || i < count; i++ {
+		if i >= 10000 {
+			return time.Time{}, false
+		}
+		if !until.IsZero() && occ.After(until) {
+			return time.Time{}, false
+		}
+		if !occ.Before(now) {
+			return occ, true
+		}
+		switch freq {
+		case "DAILY":
+			occ = occ.AddDate(0, 0, interval)
+		case "WEEKLY":
+			occ = occ.AddDate(0, 0, 7*interval)
+		case "MONTHLY":
+			occ = occ.AddDate(0, interval, 0)
+		case "YEARLY":
+			occ = occ.AddDate(interval, 0, 0)
+		default:
+			return time.Time{}, false
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseRRule extracts FREQ, INTERVAL, UNTIL and COUNT from a minimal RRULE
+// value, e.g. "FREQ=WEEKLY;INTERVAL=2;COUNT=6". count is 0 when the RRULE
+// doesn't set one, meaning "unbounded except by UNTIL".
+func parseRRule(rrule string) (freq string, interval int, until time.Time, count int) {
+	interval = 1
+	for _, part := range strings.Split(rrule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "FREQ":
+			freq = kv[1]
+		case "INTERVAL":
+			if n, err := strconv.Atoi(kv[1]); err == nil && n > 0 {
+				interval = n
+			}
+		case "UNTIL":
+			if t, err := time.Parse("20060102T150405Z", kv[1]); err == nil {
+				until = t
+			}
+		case "COUNT":
+			if n, err := strconv.Atoi(kv[1]); err == nil && n > 0 {
+				count = n
+			}
+		}
+	}
+	return freq, interval, until, count
+}
+
+// fetchVEVENTs downloads the feed at url and returns its VEVENT components.
+func fetchVEVENTs(url string) ([]vevent, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ics feed %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch ics feed %q: %v", url, resp.Status)
+	}
+	return parseVEVENTs(resp.Body)
+}
+
+// parseVEVENTs does a line-oriented parse of the VCALENDAR body, unfolding
+// continuation lines (leading space or tab, per RFC 5545 section 3.1) before
+// splitting each logical line into its property name and value.
+func parseVEVENTs(r io.Reader) ([]vevent, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+	var events []vevent
+	var cur *vevent
+	var curTZID string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			cur = &vevent{}
+			continue
+		case line == "END:VEVENT":
+			if cur != nil {
+				events = append(events, *cur)
+			}
+			cur = nil
+			continue
+		case cur == nil:
+			continue
+		}
+		name, params, value := splitICSLine(line)
+		switch name {
+		case "UID":
+			cur.uid = value
+		case "SUMMARY":
+			cur.summary = unescapeICSText(value)
+		case "DESCRIPTION":
+			cur.description = unescapeICSText(value)
+		case "LOCATION":
+			cur.location = unescapeICSText(value)
+		case "URL":
+			cur.url = value
+		case "RRULE":
+			cur.rrule = value
+		case "DTSTART":
+			curTZID = params["TZID"]
+			cur.dtstart = parseICSTime(value, curTZID)
+		case "DTEND":
+			cur.dtend = parseICSTime(value, params["TZID"])
+		}
+	}
+	return events, nil
+}
+
+// unfoldLines reverses RFC 5545 section 3.1 line folding by reattaching
+// each continuation line (leading space or tab) to its predecessor via
+// plain byte concatenation. That's only correct because folding never
+// splits a multi-byte character (see icsFoldCut in icswriter.go); a
+// continuation line's bytes always begin exactly where the prior physical
+// line's bytes were cut.
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ics feed: %w", err)
+	}
+	return lines, nil
+}
+
+// splitICSLine splits a logical line of the form "NAME;PARAM=VAL:VALUE"
+// into its property name, parameters, and value.
+func splitICSLine(line string) (name string, params map[string]string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return line, nil, ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	name = parts[0]
+	if len(parts) > 1 {
+		params = make(map[string]string, len(parts)-1)
+		for _, p := range parts[1:] {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = kv[1]
+			}
+		}
+	}
+	return name, params, value
+}
+
+// parseICSTime parses a DTSTART/DTEND value, honouring a TZID parameter or
+// the trailing "Z" UTC designator used by floating/UTC timestamps.
+func parseICSTime(value, tzid string) time.Time {
+	const localLayout = "20060102T150405"
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(localLayout+"Z", value)
+		if err != nil {
+			return time.Time{}
+		}
+		return t.UTC()
+	}
+	loc := time.UTC
+	if tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// unescapeICSText reverses the comma/semicolon/backslash/newline escaping
+// required by RFC 5545 section 3.3.11.
+func unescapeICSText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			case ',':
+				b.WriteByte(',')
+			case ';':
+				b.WriteByte(';')
+			case '\\':
+				b.WriteByte('\\')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
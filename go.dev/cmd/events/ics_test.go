@@ -0,0 +1,65 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rrule        string
+		wantFreq     string
+		wantInterval int
+		wantUntil    string
+		wantCount    int
+	}{
+		{"FREQ=WEEKLY", "WEEKLY", 1, "", 0},
+		{"FREQ=WEEKLY;INTERVAL=2", "WEEKLY", 2, "", 0},
+		{"FREQ=DAILY;UNTIL=20251231T000000Z", "DAILY", 1, "20251231T000000Z", 0},
+		{"FREQ=WEEKLY;COUNT=6", "WEEKLY", 1, "", 6},
+	}
+	for _, tc := range tests {
+		freq, interval, until, count := parseRRule(tc.rrule)
+		if freq != tc.wantFreq || interval != tc.wantInterval || count != tc.wantCount {
+			t.Errorf("parseRRule(%q) = (%q, %d, %v, %d), want freq=%q interval=%d count=%d",
+				tc.rrule, freq, interval, until, count, tc.wantFreq, tc.wantInterval, tc.wantCount)
+		}
+		if tc.wantUntil != "" {
+			want, err := time.Parse("20060102T150405Z", tc.wantUntil)
+			if err != nil {
+				t.Fatalf("bad test UNTIL %q: %v", tc.wantUntil, err)
+			}
+			if !until.Equal(want) {
+				t.Errorf("parseRRule(%q) until = %v, want %v", tc.rrule, until, want)
+			}
+		} else if !until.IsZero() {
+			t.Errorf("parseRRule(%q) until = %v, want zero", tc.rrule, until)
+		}
+	}
+}
+
+func TestNextOccurrenceCount(t *testing.T) {
+	// A 6-week course, one session per week, starting in the past: after
+	// the 6th occurrence the series is over even though FREQ/INTERVAL
+	// alone would keep producing future dates forever.
+	start := time.Date(2025, 1, 6, 18, 0, 0, 0, time.UTC) // first Monday session
+	e := vevent{
+		dtstart: start,
+		rrule:   "FREQ=WEEKLY;COUNT=6",
+	}
+	lastOccurrence := start.AddDate(0, 0, 7*5) // the 6th (final) session
+
+	now := lastOccurrence.Add(time.Hour) // just after the series ended
+	if _, ok := e.nextOccurrence(now); ok {
+		t.Errorf("nextOccurrence after the final COUNT=6 session should report none upcoming")
+	}
+
+	now = lastOccurrence.Add(-time.Hour) // just before the final session
+	got, ok := e.nextOccurrence(now)
+	if !ok || !got.Equal(lastOccurrence) {
+		t.Errorf("nextOccurrence(%v) = (%v, %v), want (%v, true)", now, got, ok, lastOccurrence)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsFoldWidth is the maximum number of octets per physical line before an
+// RFC 5545 content line must be folded (section 3.1).
+const icsFoldWidth = 75
+
+// writeICS writes ue as an RFC 5545 VCALENDAR, one VEVENT per entry, to w.
+func writeICS(w io.Writer, ue *UpcomingEvents) error {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//go.dev//cmd/events//EN")
+
+	for _, tzid := range distinctTZIDs(ue.All) {
+		writeVTimezone(&b, tzid)
+	}
+	for _, e := range ue.All {
+		writeVEvent(&b, e)
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func distinctTZIDs(events []EventData) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, e := range events {
+		if e.TZID == "" || seen[e.TZID] {
+			continue
+		}
+		seen[e.TZID] = true
+		out = append(out, e.TZID)
+	}
+	return out
+}
+
+// writeVTimezone emits a minimal VTIMEZONE for tzid, describing only the
+// zone's current UTC offset. Clients that ship the IANA database (virtually
+// all of them) resolve DTSTART/DTEND against the TZID directly; this
+// component exists so strict parsers have something to fall back to.
+func writeVTimezone(b *strings.Builder, tzid string) {
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return
+	}
+	_, offset := time.Now().In(loc).Zone()
+	writeICSLine(b, "BEGIN:VTIMEZONE")
+	writeICSLine(b, "TZID:"+tzid)
+	writeICSLine(b, "BEGIN:STANDARD")
+	writeICSLine(b, "DTSTART:19700101T000000")
+	writeICSLine(b, fmt.Sprintf("TZOFFSETFROM:%s", formatUTCOffset(offset)))
+	writeICSLine(b, fmt.Sprintf("TZOFFSETTO:%s", formatUTCOffset(offset)))
+	writeICSLine(b, "END:STANDARD")
+	writeICSLine(b, "END:VTIMEZONE")
+}
+
+func formatUTCOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, (seconds%3600)/60)
+}
+
+func writeVEvent(b *strings.Builder, e EventData) {
+	writeICSLine(b, "BEGIN:VEVENT")
+	writeICSLine(b, "UID:"+icsUID(e))
+	writeICSLine(b, "DTSTAMP:"+time.Now().UTC().Format("20060102T150405Z"))
+
+	start, err := time.Parse(time.RFC3339, e.LocalTime)
+	if err == nil {
+		layout := "20060102T150405"
+		tzid := e.TZID
+		if tzid != "" {
+			writeICSLine(b, fmt.Sprintf("DTSTART;TZID=%s:%s", tzid, start.Format(layout)))
+			writeICSLine(b, fmt.Sprintf("DTEND;TZID=%s:%s", tzid, start.Add(time.Hour).Format(layout)))
+		} else {
+			writeICSLine(b, "DTSTART:"+start.UTC().Format(layout+"Z"))
+			writeICSLine(b, "DTEND:"+start.UTC().Add(time.Hour).Format(layout+"Z"))
+		}
+	}
+
+	writeICSLine(b, "SUMMARY:"+escapeICSText(e.Name))
+	if e.Description != "" {
+		writeICSLine(b, "DESCRIPTION:"+escapeICSText(stripHTML(e.Description)))
+	}
+	if loc := icsLocation(e); loc != "" {
+		writeICSLine(b, "LOCATION:"+escapeICSText(loc))
+	}
+	if e.URL != "" {
+		writeICSLine(b, "URL:"+e.URL)
+	}
+	if e.Lat != 0 || e.Lon != 0 {
+		writeICSLine(b, fmt.Sprintf("GEO:%f;%f", e.Lat, e.Lon))
+	}
+	writeICSLine(b, "END:VEVENT")
+}
+
+// icsUID returns the UID property value for e. Meetup-sourced events only
+// carry their bare numeric event ID, so meetupSource's commonly expected
+// "@meetup.com" suffix is appended; other sources (e.g. icsSource) already
+// set ID to a full UID lifted straight from the feed's own UID property.
+func icsUID(e EventData) string {
+	if e.Source == eventSourceMeetup {
+		return e.ID + "@meetup.com"
+	}
+	return e.ID
+}
+
+func icsLocation(e EventData) string {
+	loc := e.LocalizedLocation
+	if loc == "" {
+		loc = e.City
+	}
+	parts := []string{}
+	for _, p := range []string{loc, e.State, e.Country} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stripHTML removes the small set of tags bluemonday.NewPolicy() in
+// meetupSource.Events lets through, since DESCRIPTION must be plain text.
+func stripHTML(s string) string {
+	r := strings.NewReplacer("<br/>\n", "\n", "<br/>", "\n", "<br>", "\n", "<a href=\"", "", "\" rel=\"nofollow\">", " ", "\">", " ", "</a>", "")
+	return r.Replace(s)
+}
+
+// writeICSLine appends line, folded to icsFoldWidth octets per RFC 5545
+// section 3.1 (counting a continuation line's leading space), terminated
+// with CRLF. Folds always land on a UTF-8 rune boundary (see icsFoldCut),
+// so a continuation line can be reattached to its predecessor with plain
+// byte concatenation, as unfoldLines does.
+func writeICSLine(b *strings.Builder, line string) {
+	width := icsFoldWidth
+	for len(line) > width {
+		cut := icsFoldCut(line, width)
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		width = icsFoldWidth - 1 // leave room for the leading space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// icsFoldCut returns the largest byte offset <= width that falls on a
+// UTF-8 rune boundary in s, backing off from width one byte at a time so a
+// multi-byte character (e.g. in a German, French, or Japanese SUMMARY) is
+// never split across a fold. If a single rune is itself longer than width,
+// it's returned whole rather than split.
+func icsFoldCut(s string, width int) int {
+	cut := width
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		return size
+	}
+	return cut
+}
+
+// escapeICSText escapes commas, semicolons, backslashes and newlines per
+// RFC 5545 section 3.3.11.
+func escapeICSText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`,`, `\,`,
+		`;`, `\;`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWriteICSLineFoldsOnRuneBoundary(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("x", 61) + "Zürich München café naïve"
+
+	var b strings.Builder
+	writeICSLine(&b, line)
+
+	for _, physical := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		physical = strings.TrimPrefix(physical, " ")
+		if !utf8.ValidString(physical) {
+			t.Fatalf("folded physical line is not valid UTF-8: %q", physical)
+		}
+	}
+}
+
+func TestWriteICSLineUnfoldRoundTrip(t *testing.T) {
+	want := "DESCRIPTION:" + strings.Repeat("x", 61) + "Zürich München café naïve日本語"
+
+	var b strings.Builder
+	writeICSLine(&b, want)
+
+	lines, err := unfoldLines(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("unfoldLines: %v", err)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("unfoldLines returned %d logical lines, want 1: %q", len(lines), lines)
+	}
+	if lines[0] != want {
+		t.Errorf("round-tripped line = %q, want %q", lines[0], want)
+	}
+}
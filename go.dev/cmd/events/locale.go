@@ -0,0 +1,90 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Localization holds the fields of EventData that vary per BCP-47 locale.
+// The unqualified fields on EventData remain the source of truth for
+// consumers that don't care about translation.
+type Localization struct {
+	Name              string
+	Description       string
+	LocalDate         string `yaml:"local_date"`
+	LocalizedLocation string
+}
+
+// localeMonths gives translated month names for the locales we support;
+// locales without an entry here fall back to Go's English month names (and
+// log a warning the first time they're requested).
+var localeMonths = map[string][12]string{
+	"ja-JP": {"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+	"zh-CN": {"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+	"de-DE": {"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	"es-ES": {"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	"fr-FR": {"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	"pt-BR": {"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+}
+
+// warnedLocales tracks which unsupported locale tags we've already logged a
+// fallback warning for, so a 200-group run doesn't repeat it 200 times.
+var warnedLocales sync.Map
+
+// buildLocalizations returns the requested locales' view of a single event,
+// keyed by BCP-47 tag. Locales that fail to parse as a language.Tag are
+// skipped with a logged warning rather than aborting the whole run.
+//
+// Meetup doesn't expose a per-language group description, only a per-topic
+// Lang tag (e.g. "Golang", "Web Development") that's too short to stand in
+// for one, so description is carried through unchanged for every locale
+// until there's an actual translated-description source.
+func buildLocalizations(locales []string, t time.Time, name, description, location string) map[string]Localization {
+	if len(locales) == 0 {
+		return nil
+	}
+	out := make(map[string]Localization, len(locales))
+	for _, tagStr := range locales {
+		tag, err := language.Parse(tagStr)
+		if err != nil {
+			log.Printf("skipping unrecognized locale %q: %v", tagStr, err)
+			continue
+		}
+		out[tagStr] = Localization{
+			Name:              name,
+			Description:       description,
+			LocalDate:         localizedDate(tagStr, tag, t),
+			LocalizedLocation: location,
+		}
+	}
+	return out
+}
+
+// localizedDate formats t the way EventData.LocalDate does ("Jan 2, 2006"),
+// substituting the translated month name for tagStr when we have one, and
+// using a message.Printer for tag so locale-specific digit/number
+// conventions (e.g. wide-width digits) are honored.
+func localizedDate(tagStr string, tag language.Tag, t time.Time) string {
+	months, ok := localeMonths[tagStr]
+	if !ok {
+		warnUnsupportedLocale(tagStr)
+		return t.Format("Jan 2, 2006")
+	}
+	month := months[int(t.Month())-1]
+	p := message.NewPrinter(tag)
+	return p.Sprintf("%s %d, %v", month, t.Day(), number.Decimal(t.Year(), number.NoSeparator()))
+}
+
+func warnUnsupportedLocale(tag string) {
+	if _, loaded := warnedLocales.LoadOrStore(tag, true); !loaded {
+		log.Printf("no translated month names for locale %q; falling back to English month names", tag)
+	}
+}
@@ -4,131 +4,120 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"path"
 	"strings"
 	"time"
 
-	"github.com/microcosm-cc/bluemonday"
 	"gopkg.in/yaml.v2"
+
+	"github.com/godevsite/go.dev/cmd/events/store"
 )
 
 const (
 	// eventLimit is the maximum number of events that will be output.
 	eventLimit = 15
-	// groupsSummaryPath is an API endpoint that returns global Go groups.
-	// Fetching from this API path allows to sort groups by next upcoming event.
-	groupsSummaryPath = "/pro/go/es_groups_summary?location=global&order=next_event&desc=false"
 	// eventsHeader is a header comment for the output content.
 	eventsHeader = `# DO NOT EDIT: Autogenerated from cmd/events.
 # To update, run:
 #    go run github.com/godevsite/go.dev/cmd/events > data/events.yaml`
 )
 
+var (
+	icsConfigPath = flag.String("ics-config", "", "path to a YAML file listing ICS feeds to merge in alongside Meetup groups")
+	concurrency   = flag.Int("concurrency", defaultConcurrency, "number of Meetup group requests to run in parallel")
+	cacheDir      = flag.String("cache-dir", "", "directory for the on-disk HTTP response cache; disabled if empty")
+	cacheTTL      = flag.Duration("cache-ttl", time.Hour, "how long a cached response is used before being revalidated against Meetup")
+	locales       = flag.String("locales", "", "comma-separated BCP-47 locale tags (e.g. en-US,ja-JP) to emit under EventData.Localizations")
+	format        = flag.String("format", "yaml", `output format: "yaml", "ics", or "both"`)
+	icsOut        = flag.String("ics-out", "data/events.ics", `file written for the "ics" portion of -format`)
+	storeDriver   = flag.String("store-driver", "", "if set, also persist every fetched event to this store backend (sqlite, postgres, mongo)")
+	storeDSN      = flag.String("store-dsn", "events.db", "data source name passed to -store-driver")
+)
+
 func main() {
-	c := &meetupAPI{
-		baseURL: "https://api.meetup.com",
+	// "events serve [flags]" runs the HTTP+JSON query API instead of the
+	// usual fetch-and-print flow, so it parses its own flag set.
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
 	}
-	ue, err := getUpcomingEvents(c)
-	if err != nil {
-		log.Fatal(err)
+	if len(os.Args) > 1 && os.Args[1] == "webhook" {
+		runWebhook(os.Args[2:])
+		return
 	}
-	printYAML(ue)
-}
+	flag.Parse()
 
-type client interface {
-	getGroupsSummary() (*GroupsSummary, error)
-	getGroup(urlName string) (*Group, error)
-}
-
-// getUpcomingEvents returns upcoming events globally.
-func getUpcomingEvents(c client) (*UpcomingEvents, error) {
-	summary, err := c.getGroupsSummary()
-	if err != nil {
-		return nil, err
+	api := &meetupAPI{baseURL: "https://api.meetup.com"}
+	if *cacheDir != "" {
+		api.httpClient = &http.Client{Transport: newDiskCache(*cacheDir, *cacheTTL)}
 	}
-	p := bluemonday.NewPolicy()
-	p.AllowStandardURLs()
-	p.AllowAttrs("href").OnElements("a")
-	p.AllowElements("br")
-	// Work around messy newlines in content.
-	r := strings.NewReplacer("\n", "<br/>\n", "&lt;br&gt;", "<br/>\n")
-	var events []EventData
-	for _, chapter := range summary.Chapters {
-		if len(events) >= eventLimit {
-			break
-		}
-		group, err := c.getGroup(chapter.URLName)
-		if err != nil || group.NextEvent == nil {
-			continue
+	ms := &meetupSource{c: api, concurrency: *concurrency, locales: splitLocales(*locales)}
+	if *storeDriver != "" {
+		db, err := store.Open(*storeDriver, *storeDSN)
+		if err != nil {
+			log.Fatal(err)
 		}
-		tz, err := time.LoadLocation(group.Timezone)
+		defer db.Close()
+		ms.store = db
+	}
+	sources := []EventSource{ms}
+	if *icsConfigPath != "" {
+		cfg, err := loadICSConfig(*icsConfigPath)
 		if err != nil {
-			tz = time.UTC
+			log.Fatal(err)
 		}
-		// group.NextEvent.Time is in milliseconds since UTC epoch.
-		nextEventTime := time.Unix(group.NextEvent.Time/1000, 0).In(tz)
-		events = append(events, EventData{
-			City:              chapter.City,
-			Country:           chapter.Country,
-			Description:       r.Replace(p.Sanitize(chapter.Description)), // Event descriptions are often blank, use Group description.
-			ID:                group.NextEvent.ID,
-			LocalDate:         nextEventTime.Format("Jan 2, 2006"),
-			LocalTime:         nextEventTime.Format(time.RFC3339),
-			LocalizedCountry:  group.LocalizedCountryName,
-			LocalizedLocation: group.LocalizedLocation,
-			Name:              group.NextEvent.Name,
-			State:             chapter.State,
-			ThumbnailURL:      chapter.GroupPhoto.ThumbLink,
-			URL:               "https://www.meetup.com/" + path.Join(chapter.URLName, "events", group.NextEvent.ID),
-		})
+		sources = append(sources, newICSSource(cfg))
 	}
-	return &UpcomingEvents{All: events}, nil
-}
-
-type meetupAPI struct {
-	baseURL string
-}
 
-func (c *meetupAPI) getGroupsSummary() (*GroupsSummary, error) {
-	resp, err := http.Get(c.baseURL + groupsSummaryPath)
+	ue, err := getUpcomingEvents(sources)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get events from %q: %v", groupsSummaryPath, err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get events from %q: %v", groupsSummaryPath, resp.Status)
+		log.Fatal(err)
 	}
-	var summary *GroupsSummary
-	d := json.NewDecoder(resp.Body)
-	if err := d.Decode(&summary); err != nil {
-		return summary, fmt.Errorf("failed to decode events from %q: %w", groupsSummaryPath, err)
+
+	switch *format {
+	case "yaml":
+		printYAML(ue)
+	case "ics":
+		if err := writeICSFile(*icsOut, ue); err != nil {
+			log.Fatal(err)
+		}
+	case "both":
+		printYAML(ue)
+		if err := writeICSFile(*icsOut, ue); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown -format %q: want yaml, ics, or both", *format)
 	}
-	return summary, nil
 }
 
-// getGroup fetches group details, which are useful for getting details of the next upcoming event, and timezones.
-func (c *meetupAPI) getGroup(urlName string) (*Group, error) {
-	u := c.baseURL + "/" + urlName
-	resp, err := http.Get(u)
+func writeICSFile(path string, ue *UpcomingEvents) error {
+	f, err := os.Create(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch group details from %q: %w", u, err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch group details from %q: %v", u, resp.Status)
+		return fmt.Errorf("failed to create ics output %q: %w", path, err)
 	}
+	defer f.Close()
+	return writeICS(f, ue)
+}
 
-	var group Group
-	d := json.NewDecoder(resp.Body)
-	if err := d.Decode(&group); err != nil {
-		return nil, fmt.Errorf("failed to decode group from %q: %w", u, err)
+// splitLocales turns a comma-separated -locales flag value into a slice,
+// dropping empty entries.
+func splitLocales(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			out = append(out, tag)
+		}
 	}
-	return &group, nil
+	return out
 }
 
 func printYAML(v interface{}) {
@@ -155,148 +144,24 @@ type EventData struct {
 	LocalTime         string `yaml:"local_time"`
 	LocalizedCountry  string
 	LocalizedLocation string
+	Localizations     map[string]Localization `yaml:",omitempty"`
 	Name              string
 	State             string
 	ThumbnailURL      string
 	URL               string
+	// TZID, Lat and Lon are carried through for the -format=ics writer's
+	// VTIMEZONE and GEO properties; they're not otherwise used by Hugo.
+	TZID string  `yaml:"tzid,omitempty"`
+	Lat  float64 `yaml:"lat,omitempty"`
+	Lon  float64 `yaml:"lon,omitempty"`
+	// Source identifies which EventSource produced this event (see the
+	// eventSource* constants), so the -format=ics writer knows whether ID
+	// is a bare Meetup event ID or already a full UID. It's bookkeeping,
+	// not site content, so it's left out of data/events.yaml.
+	Source string `yaml:"-"`
 }
 
-// GroupsSummary is the structure returned from /pro/go/es_groups_summary.
-type GroupsSummary struct {
-	Chapters []*Chapter
-}
-
-type Event struct {
-	Created       int    `json:"created"`
-	Description   string `json:"description"`
-	Duration      int    `json:"duration"`
-	Fee           *Fee   `json:"fee"`
-	Group         *Group `json:"group"`
-	LocalDate     string `json:"local_date"`
-	LocalTime     string `json:"local_time"`
-	ID            string `json:"id"`
-	Link          string `json:"link"`
-	Name          string `json:"name"`
-	RSVPLimit     int    `json:"rsvp_limit"`
-	Status        string `json:"status"`
-	Time          int64  `json:"time"`
-	UTCOffset     int    `json:"utc_offset"`
-	Updated       int    `json:"updated"`
-	Venue         *Venue `json:"venue"`
-	WaitlistCount int    `json:"waitlist_count"`
-	YesRSVPCount  int    `json:"yes_rsvp_count"`
-}
-
-type Venue struct {
-	Address1             string  `json:"address_1"`
-	Address2             string  `json:"address_2"`
-	Address3             string  `json:"address_3"`
-	City                 string  `json:"city"`
-	Country              string  `json:"country"`
-	ID                   int     `json:"id"`
-	Lat                  float64 `json:"lat"`
-	LocalizedCountryName string  `json:"localized_country_name"`
-	Lon                  float64 `json:"lon"`
-	Name                 string  `json:"name"`
-	Repinned             bool    `json:"repinned"`
-	State                string  `json:"state"`
-	Zip                  string  `json:"zip"`
-}
-
-type Group struct {
-	Country              string  `json:"country"`
-	Created              int     `json:"created"`
-	Description          string  `json:"description"`
-	ID                   int     `json:"id"`
-	JoinMode             string  `json:"join_mode"`
-	Lat                  float64 `json:"lat"`
-	LocalizedLocation    string  `json:"localized_location"`
-	LocalizedCountryName string  `json:"localized_country_name"`
-	Lon                  float64 `json:"lon"`
-	Name                 string  `json:"name"`
-	NextEvent            *Event  `json:"next_event"`
-	Region               string  `json:"region"`
-	Timezone             string  `json:"timezone"`
-	URLName              string  `json:"urlname"`
-	Who                  string  `json:"who"`
-}
-
-type Fee struct {
-	Accepts     string  `json:"accepts"`
-	Amount      float64 `json:"amount"`
-	Currency    string  `json:"currency"`
-	Description string  `json:"description"`
-	Label       string  `json:"label"`
-	Required    bool    `json:"required"`
-}
-
-type Chapter struct {
-	AverageAge     float64        `json:"average_age"`
-	Category       []Category     `json:"category"`
-	City           string         `json:"city"`
-	Country        string         `json:"country"`
-	Description    string         `json:"description"`
-	FoundedDate    int64          `json:"founded_date"`
-	GenderFemale   float64        `json:"gender_female"`
-	GenderMale     float64        `json:"gender_male"`
-	GenderOther    float64        `json:"gender_other"`
-	GenderUnknown  float64        `json:"gender_unknown"`
-	GroupPhoto     GroupPhoto     `json:"group_photo"`
-	ID             int            `json:"id"`
-	LastEvent      int64          `json:"last_event"`
-	Lat            float64        `json:"lat"`
-	Lon            float64        `json:"lon"`
-	MemberCount    int            `json:"member_count"`
-	Name           string         `json:"name"`
-	NextEvent      int64          `json:"next_event"`
-	OrganizerPhoto OrganizerPhoto `json:"organizer_photo"`
-	Organizers     []Organizer    `json:"organizers"`
-	PastEvents     int            `json:"past_events"`
-	PastRSVPs      int            `json:"past_rsvps"`
-	ProJoinDate    int64          `json:"pro_join_date"`
-	RSVPsPerEvent  float64        `json:"rsvps_per_event"`
-	RepeatRSVPers  int            `json:"repeat_rsvpers"`
-	State          string         `json:"state"`
-	Status         string         `json:"status"`
-	Topics         []Topic        `json:"topics"`
-	URLName        string         `json:"urlname"`
-	UpcomingEvents int            `json:"upcoming_events"`
-}
-
-type Topic struct {
-	ID     int    `json:"id"`
-	Name   string `json:"name"`
-	URLkey string `json:"urlkey"`
-	Lang   string `json:"lang"`
-}
-
-type Category struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Shortname string `json:"shortname"`
-	SortName  string `json:"sort_name"`
-}
-
-type Organizer struct {
-	Name       string `json:"name"`
-	MemberID   int    `json:"member_id"`
-	Permission string `json:"permission"`
-}
-
-type OrganizerPhoto struct {
-	BaseURL     string `json:"base_url"`
-	HighresLink string `json:"highres_link"`
-	ID          int    `json:"id"`
-	PhotoLink   string `json:"photo_link"`
-	ThumbLink   string `json:"thumb_link"`
-	Type        string `json:"type"`
-}
-
-type GroupPhoto struct {
-	BaseURL     string `json:"base_url"`
-	HighresLink string `json:"highres_link"`
-	ID          int    `json:"id"`
-	PhotoLink   string `json:"photo_link"`
-	ThumbLink   string `json:"thumb_link"`
-	Type        string `json:"type"`
-}
+const (
+	eventSourceMeetup = "meetup"
+	eventSourceICS    = "ics"
+)
@@ -0,0 +1,130 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godevsite/go.dev/cmd/events/store"
+)
+
+// runServe implements the "events serve" subcommand: a small HTTP+JSON API
+// over the events store, so the go.dev site and third-party dashboards can
+// query past attendance trends without re-hitting the Meetup API.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	driver := fs.String("store-driver", "sqlite", "store backend: sqlite, postgres, or mongo (build-tag dependent)")
+	dsn := fs.String("store-dsn", "events.db", "data source name passed to the store backend")
+	fs.Parse(args)
+
+	db, err := store.Open(*driver, *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	s := &server{store: db}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/groups/", s.handleGroup)
+	mux.HandleFunc("/stats", s.handleStats)
+
+	log.Printf("events serve: listening on %s against %s store %q", *addr, *driver, *dsn)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+type server struct {
+	store store.Store
+}
+
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := store.Filter{
+		Country: q.Get("country"),
+		Topic:   q.Get("topic"),
+	}
+	if from := q.Get("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid from=%q: %v", from, err), http.StatusBadRequest)
+			return
+		}
+		f.From = t
+	}
+	events, err := s.store.Events(r.Context(), f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, events)
+}
+
+func (s *server) handleGroup(w http.ResponseWriter, r *http.Request) {
+	urlName := strings.TrimPrefix(r.URL.Path, "/groups/")
+	if urlName == "" {
+		http.Error(w, "missing group urlname", http.StatusBadRequest)
+		return
+	}
+	events, err := s.store.GroupEvents(r.Context(), urlName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, events)
+}
+
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.store.Stats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, stats)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to encode JSON response: %v", err)
+	}
+}
+
+// eventToStore converts the richer meetup Event/Chapter/Group data (not
+// just the normalized EventData) into a store.Event for persistence.
+func eventToStore(chapter *Chapter, group *Group, event *Event) *store.Event {
+	e := &store.Event{
+		ID:            event.ID,
+		Name:          event.Name,
+		URL:           "https://www.meetup.com/" + chapter.URLName + "/events/" + event.ID,
+		GroupURLName:  chapter.URLName,
+		Country:       chapter.Country,
+		State:         chapter.State,
+		City:          chapter.City,
+		Time:          time.Unix(event.Time/1000, 0).UTC(),
+		Status:        event.Status,
+		YesRSVPCount:  event.YesRSVPCount,
+		WaitlistCount: event.WaitlistCount,
+	}
+	if len(chapter.Topics) > 0 {
+		e.Topic = chapter.Topics[0].Name
+	}
+	if event.Venue != nil {
+		e.Venue = event.Venue.Name
+	}
+	if event.Fee != nil {
+		e.Fee = event.Fee.Label + " " + strconv.FormatFloat(event.Fee.Amount, 'f', 2, 64) + " " + event.Fee.Currency
+	}
+	if len(chapter.Organizers) > 0 {
+		e.Organizer = chapter.Organizers[0].Name
+	}
+	return e
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// EventSource returns a normalized list of upcoming events from some
+// origin (a Meetup-compatible API, an iCalendar feed, etc). Implementations
+// should apply their own per-source limits, if any, and leave trimming to
+// eventLimit to the caller.
+type EventSource interface {
+	// Events returns the upcoming events known to this source. A non-nil
+	// error aborts processing of that source only; other sources still
+	// contribute their events.
+	Events() ([]EventData, error)
+}
+
+// getUpcomingEvents queries every source, merges the results, dedupes by
+// URL (falling back to ID when URL is empty), sorts by LocalTime and trims
+// to eventLimit.
+func getUpcomingEvents(sources []EventSource) (*UpcomingEvents, error) {
+	var all []EventData
+	for _, s := range sources {
+		events, err := s.Events()
+		if err != nil {
+			log.Printf("skipping event source: %v", err)
+			continue
+		}
+		all = append(all, events...)
+	}
+
+	seen := make(map[string]bool, len(all))
+	deduped := all[:0]
+	for _, e := range all {
+		key := e.URL
+		if key == "" {
+			key = e.ID
+		}
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, e)
+	}
+
+	sort.Slice(deduped, func(i, j int) bool {
+		ti, erri := time.Parse(time.RFC3339, deduped[i].LocalTime)
+		tj, errj := time.Parse(time.RFC3339, deduped[j].LocalTime)
+		if erri != nil || errj != nil {
+			return deduped[i].LocalTime < deduped[j].LocalTime
+		}
+		return ti.Before(tj)
+	})
+
+	if len(deduped) > eventLimit {
+		deduped = deduped[:eventLimit]
+	}
+	return &UpcomingEvents{All: deduped}, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build mongo
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// defaultMongoDatabase is used when dsn's URI has no path component.
+const defaultMongoDatabase = "events"
+
+func init() {
+	Register("mongo", openMongo)
+}
+
+type mongoStore struct {
+	client *mongo.Client
+	events *mongo.Collection
+}
+
+// openMongo connects to dsn (a standard mongodb:// URI); the database name
+// is taken from the URI path, defaulting to "events" if absent.
+func openMongo(dsn string) (Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dsn))
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to connect to mongo: %w", err)
+	}
+	dbName := defaultMongoDatabase
+	if cs, err := connstring.ParseAndValidate(dsn); err == nil && cs.Database != "" {
+		dbName = cs.Database
+	}
+	db := client.Database(dbName)
+	events := db.Collection("events")
+	if _, err := events.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "country", Value: 1}}},
+		{Keys: bson.D{{Key: "topic", Value: 1}}},
+		{Keys: bson.D{{Key: "time", Value: 1}}},
+	}); err != nil {
+		return nil, fmt.Errorf("store: failed to create mongo indexes: %w", err)
+	}
+	return &mongoStore{client: client, events: events}, nil
+}
+
+func (s *mongoStore) UpsertEvent(ctx context.Context, e *Event) error {
+	now := time.Now()
+	existing := s.events.FindOne(ctx, bson.D{{Key: "_id", Value: e.ID}})
+	created := now
+	var prev Event
+	if err := existing.Decode(&prev); err == nil {
+		created = prev.Created
+	}
+	e.Created, e.Updated = created, now
+	_, err := s.events.ReplaceOne(ctx, bson.D{{Key: "_id", Value: e.ID}}, mongoDoc(e), options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("store: failed to upsert event %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+func mongoDoc(e *Event) bson.D {
+	return bson.D{
+		{Key: "_id", Value: e.ID},
+		{Key: "name", Value: e.Name},
+		{Key: "url", Value: e.URL},
+		{Key: "group_urlname", Value: e.GroupURLName},
+		{Key: "country", Value: e.Country},
+		{Key: "state", Value: e.State},
+		{Key: "city", Value: e.City},
+		{Key: "topic", Value: e.Topic},
+		{Key: "time", Value: e.Time},
+		{Key: "status", Value: e.Status},
+		{Key: "yes_rsvp_count", Value: e.YesRSVPCount},
+		{Key: "waitlist_count", Value: e.WaitlistCount},
+		{Key: "venue", Value: e.Venue},
+		{Key: "fee", Value: e.Fee},
+		{Key: "organizer", Value: e.Organizer},
+		{Key: "created", Value: e.Created},
+		{Key: "updated", Value: e.Updated},
+	}
+}
+
+func (s *mongoStore) Events(ctx context.Context, f Filter) ([]*Event, error) {
+	filter := bson.D{}
+	if f.Country != "" {
+		filter = append(filter, bson.E{Key: "country", Value: f.Country})
+	}
+	if f.Topic != "" {
+		filter = append(filter, bson.E{Key: "topic", Value: f.Topic})
+	}
+	if !f.From.IsZero() {
+		filter = append(filter, bson.E{Key: "time", Value: bson.D{{Key: "$gte", Value: f.From}}})
+	}
+	if f.ExcludeStatus != "" {
+		filter = append(filter, bson.E{Key: "status", Value: bson.D{{Key: "$ne", Value: f.ExcludeStatus}}})
+	}
+	cur, err := s.events.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "time", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to query events: %w", err)
+	}
+	defer cur.Close(ctx)
+	return decodeEvents(ctx, cur)
+}
+
+func (s *mongoStore) GroupEvents(ctx context.Context, urlName string) ([]*Event, error) {
+	cur, err := s.events.Find(ctx, bson.D{{Key: "group_urlname", Value: urlName}}, options.Find().SetSort(bson.D{{Key: "time", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to query group %q: %w", urlName, err)
+	}
+	defer cur.Close(ctx)
+	return decodeEvents(ctx, cur)
+}
+
+func decodeEvents(ctx context.Context, cur *mongo.Cursor) ([]*Event, error) {
+	var events []*Event
+	for cur.Next(ctx) {
+		var e Event
+		if err := cur.Decode(&e); err != nil {
+			return nil, fmt.Errorf("store: failed to decode event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	return events, cur.Err()
+}
+
+func (s *mongoStore) Stats(ctx context.Context) (*Stats, error) {
+	total, err := s.events.CountDocuments(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to count events: %w", err)
+	}
+	groupNames, err := s.events.Distinct(ctx, "group_urlname", bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to count groups: %w", err)
+	}
+	stats := &Stats{TotalEvents: int(total), TotalGroups: len(groupNames), ByCountry: map[string]int{}}
+	countries, err := s.events.Distinct(ctx, "country", bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to list countries: %w", err)
+	}
+	for _, c := range countries {
+		country, _ := c.(string)
+		n, err := s.events.CountDocuments(ctx, bson.D{{Key: "country", Value: country}})
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to count events for %q: %w", country, err)
+		}
+		stats.ByCountry[country] = int(n)
+	}
+	return stats, nil
+}
+
+func (s *mongoStore) Close() error {
+	return s.client.Disconnect(context.Background())
+}
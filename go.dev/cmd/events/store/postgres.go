@@ -0,0 +1,162 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build postgres
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	Register("postgres", openPostgres)
+}
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	id              TEXT PRIMARY KEY,
+	name            TEXT,
+	url             TEXT,
+	group_urlname   TEXT,
+	country         TEXT,
+	state           TEXT,
+	city            TEXT,
+	topic           TEXT,
+	time            TIMESTAMPTZ,
+	status          TEXT,
+	yes_rsvp_count  INTEGER,
+	waitlist_count  INTEGER,
+	venue           TEXT,
+	fee             TEXT,
+	organizer       TEXT,
+	created         TIMESTAMPTZ,
+	updated         TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS events_country_idx ON events(country);
+CREATE INDEX IF NOT EXISTS events_topic_idx ON events(topic);
+CREATE INDEX IF NOT EXISTS events_time_idx ON events(time);
+`
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+func openPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to open postgres database: %w", err)
+	}
+	if _, err := db.Exec(postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: failed to apply postgres schema: %w", err)
+	}
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) UpsertEvent(ctx context.Context, e *Event) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO events (id, name, url, group_urlname, country, state, city, topic, time, status, yes_rsvp_count, waitlist_count, venue, fee, organizer, created, updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (id) DO UPDATE SET
+			name=excluded.name, url=excluded.url, group_urlname=excluded.group_urlname,
+			country=excluded.country, state=excluded.state, city=excluded.city, topic=excluded.topic,
+			time=excluded.time, status=excluded.status, yes_rsvp_count=excluded.yes_rsvp_count,
+			waitlist_count=excluded.waitlist_count, venue=excluded.venue, fee=excluded.fee,
+			organizer=excluded.organizer, updated=excluded.updated
+	`, e.ID, e.Name, e.URL, e.GroupURLName, e.Country, e.State, e.City, e.Topic, e.Time,
+		e.Status, e.YesRSVPCount, e.WaitlistCount, e.Venue, e.Fee, e.Organizer, now, now)
+	if err != nil {
+		return fmt.Errorf("store: failed to upsert event %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (s *postgresStore) Events(ctx context.Context, f Filter) ([]*Event, error) {
+	query := `SELECT id, name, url, group_urlname, country, state, city, topic, time, status, yes_rsvp_count, waitlist_count, venue, fee, organizer, created, updated FROM events WHERE 1=1`
+	var args []interface{}
+	if f.Country != "" {
+		args = append(args, f.Country)
+		query += fmt.Sprintf(" AND country = $%d", len(args))
+	}
+	if f.Topic != "" {
+		args = append(args, f.Topic)
+		query += fmt.Sprintf(" AND topic = $%d", len(args))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		query += fmt.Sprintf(" AND time >= $%d", len(args))
+	}
+	if f.ExcludeStatus != "" {
+		args = append(args, f.ExcludeStatus)
+		query += fmt.Sprintf(" AND status != $%d", len(args))
+	}
+	query += " ORDER BY time DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to query events: %w", err)
+	}
+	defer rows.Close()
+	return scanPostgresEvents(rows)
+}
+
+func (s *postgresStore) GroupEvents(ctx context.Context, urlName string) ([]*Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, url, group_urlname, country, state, city, topic, time, status, yes_rsvp_count, waitlist_count, venue, fee, organizer, created, updated
+		FROM events WHERE group_urlname = $1 ORDER BY time DESC`, urlName)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to query group %q: %w", urlName, err)
+	}
+	defer rows.Close()
+	return scanPostgresEvents(rows)
+}
+
+func scanPostgresEvents(rows *sql.Rows) ([]*Event, error) {
+	var events []*Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Name, &e.URL, &e.GroupURLName, &e.Country, &e.State, &e.City,
+			&e.Topic, &e.Time, &e.Status, &e.YesRSVPCount, &e.WaitlistCount, &e.Venue, &e.Fee,
+			&e.Organizer, &e.Created, &e.Updated); err != nil {
+			return nil, fmt.Errorf("store: failed to scan event row: %w", err)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func (s *postgresStore) Stats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{ByCountry: map[string]int{}}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events`).Scan(&stats.TotalEvents); err != nil {
+		return nil, fmt.Errorf("store: failed to count events: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT group_urlname) FROM events`).Scan(&stats.TotalGroups); err != nil {
+		return nil, fmt.Errorf("store: failed to count groups: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT country, COUNT(*) FROM events GROUP BY country`)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to group events by country: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var country string
+		var count int
+		if err := rows.Scan(&country, &count); err != nil {
+			return nil, fmt.Errorf("store: failed to scan country count: %w", err)
+		}
+		stats.ByCountry[country] = count
+	}
+	return stats, rows.Err()
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	Register("sqlite", openSQLite)
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	id              TEXT PRIMARY KEY,
+	name            TEXT,
+	url             TEXT,
+	group_urlname   TEXT,
+	country         TEXT,
+	state           TEXT,
+	city            TEXT,
+	topic           TEXT,
+	time            TIMESTAMP,
+	status          TEXT,
+	yes_rsvp_count  INTEGER,
+	waitlist_count  INTEGER,
+	venue           TEXT,
+	fee             TEXT,
+	organizer       TEXT,
+	created         TIMESTAMP,
+	updated         TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS events_country_idx ON events(country);
+CREATE INDEX IF NOT EXISTS events_topic_idx ON events(topic);
+CREATE INDEX IF NOT EXISTS events_time_idx ON events(time);
+`
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func openSQLite(dsn string) (Store, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to open sqlite database %q: %w", dsn, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: failed to apply sqlite schema: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) UpsertEvent(ctx context.Context, e *Event) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO events (id, name, url, group_urlname, country, state, city, topic, time, status, yes_rsvp_count, waitlist_count, venue, fee, organizer, created, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name=excluded.name, url=excluded.url, group_urlname=excluded.group_urlname,
+			country=excluded.country, state=excluded.state, city=excluded.city, topic=excluded.topic,
+			time=excluded.time, status=excluded.status, yes_rsvp_count=excluded.yes_rsvp_count,
+			waitlist_count=excluded.waitlist_count, venue=excluded.venue, fee=excluded.fee,
+			organizer=excluded.organizer, updated=excluded.updated
+	`, e.ID, e.Name, e.URL, e.GroupURLName, e.Country, e.State, e.City, e.Topic, e.Time,
+		e.Status, e.YesRSVPCount, e.WaitlistCount, e.Venue, e.Fee, e.Organizer, now, now)
+	if err != nil {
+		return fmt.Errorf("store: failed to upsert event %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Events(ctx context.Context, f Filter) ([]*Event, error) {
+	query := `SELECT id, name, url, group_urlname, country, state, city, topic, time, status, yes_rsvp_count, waitlist_count, venue, fee, organizer, created, updated FROM events WHERE 1=1`
+	var args []interface{}
+	if f.Country != "" {
+		query += " AND country = ?"
+		args = append(args, f.Country)
+	}
+	if f.Topic != "" {
+		query += " AND topic = ?"
+		args = append(args, f.Topic)
+	}
+	if !f.From.IsZero() {
+		query += " AND time >= ?"
+		args = append(args, f.From)
+	}
+	if f.ExcludeStatus != "" {
+		query += " AND status != ?"
+		args = append(args, f.ExcludeStatus)
+	}
+	query += " ORDER BY time DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to query events: %w", err)
+	}
+	defer rows.Close()
+	return scanEvents(rows)
+}
+
+func (s *sqliteStore) GroupEvents(ctx context.Context, urlName string) ([]*Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, url, group_urlname, country, state, city, topic, time, status, yes_rsvp_count, waitlist_count, venue, fee, organizer, created, updated
+		FROM events WHERE group_urlname = ? ORDER BY time DESC`, urlName)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to query group %q: %w", urlName, err)
+	}
+	defer rows.Close()
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]*Event, error) {
+	var events []*Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Name, &e.URL, &e.GroupURLName, &e.Country, &e.State, &e.City,
+			&e.Topic, &e.Time, &e.Status, &e.YesRSVPCount, &e.WaitlistCount, &e.Venue, &e.Fee,
+			&e.Organizer, &e.Created, &e.Updated); err != nil {
+			return nil, fmt.Errorf("store: failed to scan event row: %w", err)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func (s *sqliteStore) Stats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{ByCountry: map[string]int{}}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events`).Scan(&stats.TotalEvents); err != nil {
+		return nil, fmt.Errorf("store: failed to count events: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT group_urlname) FROM events`).Scan(&stats.TotalGroups); err != nil {
+		return nil, fmt.Errorf("store: failed to count groups: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT country, COUNT(*) FROM events GROUP BY country`)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to group events by country: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var country string
+		var count int
+		if err := rows.Scan(&country, &count); err != nil {
+			return nil, fmt.Errorf("store: failed to scan country count: %w", err)
+		}
+		stats.ByCountry[country] = count
+	}
+	return stats, rows.Err()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
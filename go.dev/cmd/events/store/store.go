@@ -0,0 +1,98 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package store records every event cmd/events has observed, not just the
+// upcoming eventLimit, so callers can query historical attendance trends.
+// The default build links in a SQLite backend; Postgres and MongoDB
+// backends are opt-in via the "postgres" and "mongo" build tags, each
+// registering itself through Register in an init function.
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Event is a single observed Meetup event, upserted by ID on every run.
+// The bson tags pin the field names the mongo backend (mongo.go) reads and
+// writes; without them the driver's default lowercased-no-separator naming
+// would desync from the names mongoDoc actually writes to the collection.
+type Event struct {
+	ID            string    `bson:"_id"`
+	Name          string    `bson:"name"`
+	URL           string    `bson:"url"`
+	GroupURLName  string    `bson:"group_urlname"`
+	Country       string    `bson:"country"`
+	State         string    `bson:"state"`
+	City          string    `bson:"city"`
+	Topic         string    `bson:"topic"`
+	Time          time.Time `bson:"time"`
+	Status        string    `bson:"status"`
+	YesRSVPCount  int       `bson:"yes_rsvp_count"`
+	WaitlistCount int       `bson:"waitlist_count"`
+	Venue         string    `bson:"venue"`
+	Fee           string    `bson:"fee"`
+	Organizer     string    `bson:"organizer"`
+	// Created and Updated are store-maintained: Created is set on first
+	// insert, Updated is bumped on every upsert thereafter.
+	Created time.Time `bson:"created"`
+	Updated time.Time `bson:"updated"`
+}
+
+// Filter narrows an Events query. Zero-valued fields are not filtered on.
+type Filter struct {
+	Country string
+	Topic   string
+	From    time.Time
+	// ExcludeStatus, if set, omits events whose Status matches it (e.g.
+	// "cancelled").
+	ExcludeStatus string
+}
+
+// Stats summarizes everything a backend has recorded.
+type Stats struct {
+	TotalEvents int
+	TotalGroups int
+	ByCountry   map[string]int
+}
+
+// Store is implemented by each backend (sqlite.go, postgres.go, mongo.go).
+type Store interface {
+	// UpsertEvent inserts e, or updates it in place by e.ID, preserving
+	// the original Created timestamp.
+	UpsertEvent(ctx context.Context, e *Event) error
+	// Events returns events matching f, most recent first.
+	Events(ctx context.Context, f Filter) ([]*Event, error)
+	// GroupEvents returns every event recorded for urlName, most recent first.
+	GroupEvents(ctx context.Context, urlName string) ([]*Event, error)
+	Stats(ctx context.Context) (*Stats, error)
+	Close() error
+}
+
+// Open returns a Store for driver ("sqlite", "postgres", or "mongo",
+// depending on which backends were compiled in) connected to dsn.
+func Open(driver, dsn string) (Store, error) {
+	open, ok := backends[driver]
+	if !ok {
+		return nil, fmt.Errorf("store: unknown driver %q (want one of: %s)", driver, availableDrivers())
+	}
+	return open(dsn)
+}
+
+var backends = map[string]func(dsn string) (Store, error){}
+
+// Register makes a backend available under name. Backend implementations
+// call this from an init function.
+func Register(name string, open func(dsn string) (Store, error)) {
+	backends[name] = open
+}
+
+func availableDrivers() string {
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	return fmt.Sprint(names)
+}
@@ -0,0 +1,234 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/godevsite/go.dev/cmd/events/store"
+)
+
+// runWebhook implements the "events webhook" subcommand: an HTTP server
+// that accepts signed push notifications describing created/updated/
+// cancelled events, so a newly announced meetup can appear on go.dev
+// within seconds instead of waiting for the next cron run.
+func runWebhook(args []string) {
+	fs := flag.NewFlagSet("webhook", flag.ExitOnError)
+	addr := fs.String("addr", ":8081", "address to listen on")
+	secret := fs.String("webhook-secret", "", "shared secret used to verify the X-Signature header (required)")
+	driver := fs.String("store-driver", "sqlite", "store backend: sqlite, postgres, or mongo (build-tag dependent)")
+	dsn := fs.String("store-dsn", "events.db", "data source name passed to the store backend")
+	dataPath := fs.String("data-path", "data/events.yaml", "events.yaml path regenerated after each accepted webhook")
+	rebuildHook := fs.String("rebuild-hook", "", "shell command, or http(s) URL to POST, run after data-path is regenerated")
+	fs.Parse(args)
+
+	if *secret == "" {
+		log.Fatal("events webhook: -webhook-secret is required")
+	}
+	db, err := store.Open(*driver, *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	h := &webhookHandler{
+		secret:      []byte(*secret),
+		store:       db,
+		dataPath:    *dataPath,
+		rebuildHook: *rebuildHook,
+	}
+	log.Printf("events webhook: listening on %s against %s store %q", *addr, *driver, *dsn)
+	log.Fatal(http.ListenAndServe(*addr, h))
+}
+
+// webhookPayload is the push notification body: a create/update/cancel for
+// a single event.
+type webhookPayload struct {
+	Type  string       `json:"type"` // "created", "updated", or "cancelled"
+	Event webhookEvent `json:"event"`
+}
+
+type webhookEvent struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	URL           string  `json:"url"`
+	GroupURLName  string  `json:"group_urlname"`
+	Country       string  `json:"country"`
+	State         string  `json:"state"`
+	City          string  `json:"city"`
+	Topic         string  `json:"topic"`
+	Time          int64   `json:"time"` // milliseconds since UTC epoch, as in the Meetup API
+	Timezone      string  `json:"timezone"`
+	YesRSVPCount  int     `json:"yes_rsvp_count"`
+	WaitlistCount int     `json:"waitlist_count"`
+	Venue         string  `json:"venue"`
+	Fee           string  `json:"fee"`
+	Organizer     string  `json:"organizer"`
+	LocalizedLoc  string  `json:"localized_location"`
+	Lat           float64 `json:"lat"`
+	Lon           float64 `json:"lon"`
+}
+
+type webhookHandler struct {
+	secret      []byte
+	store       store.Store
+	dataPath    string
+	rebuildHook string
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if !h.validSignature(body, r.Header.Get("X-Signature")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	e := &store.Event{
+		ID:            payload.Event.ID,
+		Name:          payload.Event.Name,
+		URL:           payload.Event.URL,
+		GroupURLName:  payload.Event.GroupURLName,
+		Country:       payload.Event.Country,
+		State:         payload.Event.State,
+		City:          payload.Event.City,
+		Topic:         payload.Event.Topic,
+		Time:          time.UnixMilli(payload.Event.Time).UTC(),
+		Status:        payload.Type,
+		YesRSVPCount:  payload.Event.YesRSVPCount,
+		WaitlistCount: payload.Event.WaitlistCount,
+		Venue:         payload.Event.Venue,
+		Fee:           payload.Event.Fee,
+		Organizer:     payload.Event.Organizer,
+	}
+	if err := h.store.UpsertEvent(r.Context(), e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.regenerateDataFile(r.Context()); err != nil {
+		log.Printf("failed to regenerate %s: %v", h.dataPath, err)
+		http.Error(w, "event stored but data file regeneration failed", http.StatusInternalServerError)
+		return
+	}
+	if h.rebuildHook != "" {
+		if err := runRebuildHook(h.rebuildHook); err != nil {
+			log.Printf("rebuild hook failed: %v", err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validSignature reports whether signature is the hex-encoded HMAC-SHA256
+// of body using h.secret, compared in constant time.
+func (h *webhookHandler) validSignature(body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(want))
+}
+
+// regenerateDataFile rewrites dataPath from the store's current upcoming
+// events, atomically: write to a tmp file in the same directory, fsync,
+// then rename over the target so concurrent readers (e.g. a Hugo build)
+// never observe a partial write.
+func (h *webhookHandler) regenerateDataFile(ctx context.Context) error {
+	events, err := h.store.Events(ctx, store.Filter{From: time.Now(), ExcludeStatus: "cancelled"})
+	if err != nil {
+		return fmt.Errorf("failed to query upcoming events: %w", err)
+	}
+	// Store.Events returns most-recent-first; we want soonest-first.
+	sort.Slice(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if len(events) > eventLimit {
+		events = events[:eventLimit]
+	}
+	ue := &UpcomingEvents{All: make([]EventData, len(events))}
+	for i, e := range events {
+		ue.All[i] = EventData{
+			City:      e.City,
+			Country:   e.Country,
+			ID:        e.ID,
+			LocalDate: e.Time.Format("Jan 2, 2006"),
+			LocalTime: e.Time.Format(time.RFC3339),
+			Name:      e.Name,
+			State:     e.State,
+			URL:       e.URL,
+		}
+	}
+
+	dir := filepath.Dir(h.dataPath)
+	tmp, err := ioutil.TempFile(dir, ".events-*.yaml.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	fmt.Fprintln(tmp, eventsHeader)
+	if err := yaml.NewEncoder(tmp).Encode(ue); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode yaml: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to fsync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), h.dataPath); err != nil {
+		return fmt.Errorf("failed to rename temp file into place: %w", err)
+	}
+	return nil
+}
+
+// runRebuildHook triggers a Hugo rebuild: hook is POSTed to if it looks like
+// an HTTP(S) URL, otherwise it's run as a shell command.
+func runRebuildHook(hook string) error {
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		resp, err := http.Post(hook, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			return fmt.Errorf("failed to POST rebuild hook %q: %w", hook, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("rebuild hook %q returned %v", hook, resp.Status)
+		}
+		return nil
+	}
+	cmd := exec.Command("sh", "-c", hook)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("rebuild hook %q failed: %w (output: %s)", hook, err, out)
+	}
+	return nil
+}